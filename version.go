@@ -2,8 +2,11 @@ package ssh3
 
 import (
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog/log"
 )
@@ -38,10 +41,125 @@ func ThisVersion() Version {
 			ExperimentalSpecVersion: PROTOCOL_EXPERIMENTAL_SPEC_VERSION,
 		},
 		softwareVersion: SoftwareVersion{
-			Major: SOFTWARE_MAJOR,
-			Minor: SOFTWARE_MINOR,
-			Patch: SOFTWARE_PATCH,
+			ImplementationName: SOFTWARE_IMPLEMENTATION_NAME,
+			Major:              SOFTWARE_MAJOR,
+			Minor:              SOFTWARE_MINOR,
+			Patch:              SOFTWARE_PATCH,
 		},
+		recommendedProtocolVersion: announcedRecommendedProtocolVersion.Load(),
+		requiredProtocolVersion:    announcedRequiredProtocolVersion.Load(),
+	}
+}
+
+// announcedRecommendedProtocolVersion and announcedRequiredProtocolVersion are the values
+// that GetCurrentVersionString() and ThisVersion() advertise to peers. They are nil
+// (nothing announced) unless an operator calls SetAnnouncedRecommendedProtocolVersion()
+// or SetAnnouncedRequiredProtocolVersion(), typically on startup of a long-lived server.
+// They are read on every handshake and may be set concurrently with that, hence the atomic
+// pointers rather than plain vars.
+var announcedRecommendedProtocolVersion atomic.Pointer[ProtocolVersion]
+var announcedRequiredProtocolVersion atomic.Pointer[ProtocolVersion]
+
+// SetAnnouncedRecommendedProtocolVersion sets the protocol version that this process will
+// advertise to peers as recommended, to nudge them towards upgrading.
+func SetAnnouncedRecommendedProtocolVersion(pv ProtocolVersion) {
+	announcedRecommendedProtocolVersion.Store(&pv)
+}
+
+// SetAnnouncedRequiredProtocolVersion sets the protocol version that this process will
+// advertise to peers as the minimum it supports, distinct from IsVersionSupported()'s own
+// strict yes/no check: this is advisory information a peer can act on proactively.
+func SetAnnouncedRequiredProtocolVersion(pv ProtocolVersion) {
+	announcedRequiredProtocolVersion.Store(&pv)
+}
+
+// OnRecommendedVersionAvailable, when non-nil, is invoked by ParseVersionString() whenever
+// a peer announces a recommendedProtocolVersion. Servers and clients of long-lived SSH3
+// deployments can set this hook to nudge users towards upgrading.
+var OnRecommendedVersionAvailable func(peer *Version, recommended ProtocolVersion)
+
+// OnRequiredVersionAvailable, when non-nil, is invoked by ParseVersionString() whenever a
+// peer announces a requiredProtocolVersion, so that callers can hard-fail when their own
+// version falls under that floor.
+var OnRequiredVersionAvailable func(peer *Version, required ProtocolVersion)
+
+// recommendedVersionDeltaGauge and requiredVersionDeltaGauge track, as a Prometheus-style
+// gauge, the gap between ThisVersion()'s protocol version and the last recommended/required
+// protocol version announced by a peer. A positive delta means the peer is ahead of us.
+var recommendedVersionDeltaGauge = newVersionDeltaGauge("ssh3_recommended_protocol_version_delta")
+var requiredVersionDeltaGauge = newVersionDeltaGauge("ssh3_required_protocol_version_delta")
+
+// RecommendedVersionDelta returns the current value of the recommended_delta gauge.
+func RecommendedVersionDelta() int64 {
+	return recommendedVersionDeltaGauge.Get()
+}
+
+// RequiredVersionDelta returns the current value of the required_delta gauge.
+func RequiredVersionDelta() int64 {
+	return requiredVersionDeltaGauge.Get()
+}
+
+// protocolVersionOrdinal reduces a protocol version to a single comparable integer, major
+// taking precedence over minor, so gauges and deltas can be computed with plain arithmetic.
+func protocolVersionOrdinal(pv ProtocolVersion) int64 {
+	return int64(pv.Major)*1000 + int64(pv.Minor)
+}
+
+// versionDeltaGauge is a minimal, dependency-free stand-in for a Prometheus gauge: a single
+// int64 value that can be set/read concurrently and rendered in the Prometheus text
+// exposition format via WriteTo. It exists so this package does not have to pull in a
+// metrics client just to expose two numbers; a binary embedding ssh3 is free to mirror
+// RecommendedVersionDelta()/RequiredVersionDelta() into its own real Prometheus registry.
+type versionDeltaGauge struct {
+	name string
+	mu   sync.Mutex
+	set  bool
+	val  int64
+}
+
+func newVersionDeltaGauge(name string) *versionDeltaGauge {
+	return &versionDeltaGauge{name: name}
+}
+
+func (g *versionDeltaGauge) Set(v int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.val, g.set = v, true
+}
+
+func (g *versionDeltaGauge) Get() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.val
+}
+
+// WriteTo renders the gauge in the Prometheus text exposition format. It writes nothing if
+// the gauge has never been set, since no peer announcement was observed yet.
+func (g *versionDeltaGauge) WriteTo(w io.Writer) (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.set {
+		return 0, nil
+	}
+	n, err := fmt.Fprintf(w, "%s %d\n", g.name, g.val)
+	return int64(n), err
+}
+
+// checkVersionAnnouncement runs the recommended/required-version hooks and refreshes the
+// delta gauges for a peer Version that was just parsed by ParseVersionString().
+func checkVersionAnnouncement(peer *Version) {
+	this := ThisVersion()
+	if peer.recommendedProtocolVersion != nil {
+		recommendedVersionDeltaGauge.Set(protocolVersionOrdinal(*peer.recommendedProtocolVersion) - protocolVersionOrdinal(this.protocolVersion))
+		if OnRecommendedVersionAvailable != nil {
+			OnRecommendedVersionAvailable(peer, *peer.recommendedProtocolVersion)
+		}
+	}
+	if peer.requiredProtocolVersion != nil {
+		requiredVersionDeltaGauge.Set(protocolVersionOrdinal(*peer.requiredProtocolVersion) - protocolVersionOrdinal(this.protocolVersion))
+		if OnRequiredVersionAvailable != nil {
+			OnRequiredVersionAvailable(peer, *peer.requiredProtocolVersion)
+		}
 	}
 }
 
@@ -56,11 +174,11 @@ func IsVersionSupported(other *Version) bool {
 		return false
 	}
 
-	// special case: to our knowledge, experimental spec version older than alpha-00 are only implemented by us (i.e. francoismichel/ssh3)
-	if other.protocolVersion.ExperimentalSpecVersion == "" && other.softwareVersion.ImplementationName == SOFTWARE_IMPLEMENTATION_NAME &&
-		other.softwareVersion.Major == 0 && other.softwareVersion.Minor == 1 && other.softwareVersion.Patch <= 5 {
-		// then, only support software version >= 0.1.4
-		return other.softwareVersion.Patch >= 4
+	// if a constraint was registered for this (implementation, spec version) pair, defer to it
+	// instead of the hard-coded rules below. This is how operators and library users declare
+	// their own compatibility windows without patching IsVersionSupported().
+	if c, ok := lookupCompatibility(other.softwareVersion.ImplementationName, other.protocolVersion.ExperimentalSpecVersion); ok {
+		return c.Matches(other)
 	}
 
 	// Starting from here, we have proper experimental spec version signalling.
@@ -70,6 +188,325 @@ func IsVersionSupported(other *Version) bool {
 	return other.protocolVersion.ExperimentalSpecVersion == "alpha-00"
 }
 
+func init() {
+	// special case: to our knowledge, experimental spec version older than alpha-00 are only
+	// implemented by us (i.e. francoismichel/ssh3), and only software version >= 0.1.4 of those
+	// pre-signalling clients are supported. This used to be hard-coded in IsVersionSupported.
+	RegisterCompatibility(SOFTWARE_IMPLEMENTATION_NAME, "", MustParseVersionConstraint(">=0.1.4, <=0.1.5"))
+}
+
+// VersionConstraint is a parsed, evaluatable compatibility expression such as
+// ">=0.1.4, <0.2.0" or "^3.0_alpha-00". It lets operators and library users declare which
+// peer versions should be accepted, the same way go-version/go-semver constraint objects
+// gate dependency resolution, without having to patch IsVersionSupported() itself.
+type VersionConstraint struct {
+	terms []constraintTerm
+}
+
+type constraintOp int
+
+const (
+	constraintEQ constraintOp = iota
+	constraintGE
+	constraintGT
+	constraintLE
+	constraintLT
+	constraintCaret
+)
+
+type constraintTerm struct {
+	op  constraintOp
+	ref comparableVersion
+}
+
+// comparableVersion is the common shape a constraint operand is reduced to, whether it was
+// written as a software version (e.g. "0.1.4") or a protocol version (e.g. "3.0_alpha-00").
+type comparableVersion struct {
+	isProtocol bool
+	major      int
+	minor      int
+	patch      int
+	spec       string
+}
+
+func parseComparableVersion(s string) (comparableVersion, error) {
+	if strings.ContainsRune(s, '_') {
+		pv, err := ParseProtocolVersion(s)
+		if err != nil {
+			return comparableVersion{}, err
+		}
+		return comparableVersion{isProtocol: true, major: pv.Major, minor: pv.Minor, spec: pv.ExperimentalSpecVersion}, nil
+	}
+	sv, err := ParseSoftwareVersion("", s)
+	if err != nil {
+		return comparableVersion{}, err
+	}
+	return comparableVersion{major: sv.Major, minor: sv.Minor, patch: sv.Patch}, nil
+}
+
+// asComparableVersion reduces a peer Version down to the comparableVersion shape matching
+// ref, so a single constraint term can be checked against either the software or the
+// protocol version depending on how the operand was written.
+func (v Version) asComparableVersion(ref comparableVersion) comparableVersion {
+	if ref.isProtocol {
+		return comparableVersion{isProtocol: true, major: v.protocolVersion.Major, minor: v.protocolVersion.Minor, spec: v.protocolVersion.ExperimentalSpecVersion}
+	}
+	return comparableVersion{major: v.softwareVersion.Major, minor: v.softwareVersion.Minor, patch: v.softwareVersion.Patch}
+}
+
+// compare returns -1, 0 or 1 depending on whether a is smaller than, equal to, or greater
+// than b, comparing major, then minor, then patch/spec.
+func (a comparableVersion) compare(b comparableVersion) int {
+	if a.major != b.major {
+		return cmpInt(a.major, b.major)
+	}
+	if a.minor != b.minor {
+		return cmpInt(a.minor, b.minor)
+	}
+	if a.isProtocol {
+		return strings.Compare(a.spec, b.spec)
+	}
+	return cmpInt(a.patch, b.patch)
+}
+
+func cmpInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type InvalidVersionConstraint struct {
+	constraintString string
+}
+
+func (e InvalidVersionConstraint) Error() string {
+	return fmt.Sprintf("invalid version constraint: %s", e.constraintString)
+}
+
+// ParseVersionConstraint parses a comma-separated list of constraint terms such as
+// ">=0.1.4, <0.2.0" (all terms must match) or a single caret range such as
+// "^3.0_alpha-00" (same major version, greater than or equal to the given one). Each term
+// operand may be either a software version ("major.minor.patch") or a protocol version
+// ("major.minor_specVersion").
+func ParseVersionConstraint(constraintString string) (VersionConstraint, error) {
+	var constraint VersionConstraint
+	for _, rawTerm := range strings.Split(constraintString, ",") {
+		term := strings.TrimSpace(rawTerm)
+		if term == "" {
+			continue
+		}
+		var op constraintOp
+		var operand string
+		switch {
+		case strings.HasPrefix(term, ">="):
+			op, operand = constraintGE, term[2:]
+		case strings.HasPrefix(term, "<="):
+			op, operand = constraintLE, term[2:]
+		case strings.HasPrefix(term, ">"):
+			op, operand = constraintGT, term[1:]
+		case strings.HasPrefix(term, "<"):
+			op, operand = constraintLT, term[1:]
+		case strings.HasPrefix(term, "^"):
+			op, operand = constraintCaret, term[1:]
+		case strings.HasPrefix(term, "="):
+			op, operand = constraintEQ, term[1:]
+		default:
+			op, operand = constraintEQ, term
+		}
+		ref, err := parseComparableVersion(strings.TrimSpace(operand))
+		if err != nil {
+			return VersionConstraint{}, InvalidVersionConstraint{constraintString: constraintString}
+		}
+		constraint.terms = append(constraint.terms, constraintTerm{op: op, ref: ref})
+	}
+	if len(constraint.terms) == 0 {
+		return VersionConstraint{}, InvalidVersionConstraint{constraintString: constraintString}
+	}
+	return constraint, nil
+}
+
+// MustParseVersionConstraint is like ParseVersionConstraint but panics on error. It is
+// meant for package-level constraint literals registered from init().
+func MustParseVersionConstraint(constraintString string) VersionConstraint {
+	c, err := ParseVersionConstraint(constraintString)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// Matches reports whether v satisfies every term of the constraint.
+func (c VersionConstraint) Matches(v *Version) bool {
+	for _, term := range c.terms {
+		candidate := v.asComparableVersion(term.ref)
+		cmp := candidate.compare(term.ref)
+		switch term.op {
+		case constraintEQ:
+			if cmp != 0 {
+				return false
+			}
+		case constraintGE:
+			if cmp < 0 {
+				return false
+			}
+		case constraintGT:
+			if cmp <= 0 {
+				return false
+			}
+		case constraintLE:
+			if cmp > 0 {
+				return false
+			}
+		case constraintLT:
+			if cmp >= 0 {
+				return false
+			}
+		case constraintCaret:
+			if candidate.major != term.ref.major || cmp < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+var compatibilityRegistryMu sync.RWMutex
+var compatibilityRegistry = map[compatibilityKey]VersionConstraint{}
+
+type compatibilityKey struct {
+	implementationName  string
+	protocolSpecVersion string
+}
+
+// RegisterCompatibility declares the VersionConstraint that a peer announcing
+// implementation name impl and experimental spec version protocolSpec must satisfy for
+// IsVersionSupported() to accept it. It lets downstream forks or SSH3 gateways extend the
+// compatibility rules of this package at runtime, instead of patching IsVersionSupported().
+func RegisterCompatibility(impl string, protocolSpec string, c VersionConstraint) {
+	compatibilityRegistryMu.Lock()
+	defer compatibilityRegistryMu.Unlock()
+	compatibilityRegistry[compatibilityKey{implementationName: impl, protocolSpecVersion: protocolSpec}] = c
+}
+
+func lookupCompatibility(impl string, protocolSpec string) (VersionConstraint, bool) {
+	compatibilityRegistryMu.RLock()
+	defer compatibilityRegistryMu.RUnlock()
+	c, ok := compatibilityRegistry[compatibilityKey{implementationName: impl, protocolSpecVersion: protocolSpec}]
+	return c, ok
+}
+
+// SSHServerSupportedVersionsHeader is the HTTP header (or HTTP/3 trailer) through which
+// an SSH3 server advertises the set of protocol versions it is willing to accept during
+// the CONNECT-style upgrade. Clients that receive an UnsupportedSSHVersion error can use
+// this header as input to NegotiateVersion() instead of giving up immediately.
+const SSHServerSupportedVersionsHeader string = "Server-Supported-Versions"
+
+// SupportedVersions returns the list of versions that this implementation is prepared to
+// accept from a peer. Right now this is simply ThisVersion(): the pre-signalling versions
+// that IsVersionSupported() also accepts through a registered VersionConstraint are not
+// listed. It gives the server something concrete to advertise to clients and is the usual
+// local argument to NegotiateVersion().
+func SupportedVersions() []Version {
+	return []Version{ThisVersion()}
+}
+
+// EncodeSupportedVersionsHeader serializes versions into the value a server should set on
+// the SSHServerSupportedVersionsHeader, one GetCurrentVersionString()-style entry per
+// version, comma-separated. Entries are not escaped: a version whose implementation name or
+// spec version contains a comma does not round-trip through ParseSupportedVersionsHeader().
+func EncodeSupportedVersionsHeader(versions []Version) string {
+	entries := make([]string, 0, len(versions))
+	for _, v := range versions {
+		entries = append(entries, v.versionString())
+	}
+	return strings.Join(entries, ",")
+}
+
+// ParseSupportedVersionsHeader parses a SSHServerSupportedVersionsHeader value produced by
+// EncodeSupportedVersionsHeader back into the list of versions it advertises, so a client
+// can pass the result as the remoteAdvertised argument to NegotiateVersion(). Entries are
+// parsed with parseVersionFields rather than ParseVersionString: the header by design may
+// list versions the local implementation does not itself support (that is the whole point
+// of NegotiateVersion picking the overlap), so a malformed entry still fails the whole
+// parse, but an entry that simply is not locally supported must not.
+func ParseSupportedVersionsHeader(header string) ([]Version, error) {
+	var versions []Version
+	for _, entry := range strings.Split(header, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		version, err := parseVersionFields(entry)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, *version)
+	}
+	return versions, nil
+}
+
+// versionString reformats v the way GetCurrentVersionString() formats ThisVersion(), so a
+// server can advertise any Version it holds (not just its own), e.g. each entry of
+// SupportedVersions() in the SSHServerSupportedVersionsHeader.
+func (v Version) versionString() string {
+	s := fmt.Sprintf("%s %d.%d %s %s experimental_spec_version=%s",
+		v.protocolName, v.protocolVersion.Major, v.protocolVersion.Minor,
+		v.softwareVersion.ImplementationName, v.softwareVersion.String(), v.protocolVersion.ExperimentalSpecVersion)
+	if v.recommendedProtocolVersion != nil {
+		s += fmt.Sprintf(" recommended_version=%s", v.recommendedProtocolVersion.String())
+	}
+	if v.requiredProtocolVersion != nil {
+		s += fmt.Sprintf(" required_version=%s", v.requiredProtocolVersion.String())
+	}
+	return s
+}
+
+// NegotiateVersion picks, among the versions advertised by a peer (remoteAdvertised), the
+// one that the local implementation also supports. It is meant to be called by a client
+// after receiving an UnsupportedSSHVersion error along with a Server-Supported-Versions
+// header/trailer: instead of hard-failing on a strict equality check, the client retries
+// the request using the version returned here.
+//
+// local is the set of versions the caller is itself willing to use (typically
+// SupportedVersions()). If several versions overlap, the one in remoteAdvertised with the
+// highest software version is returned; protocol versions are not compared against each
+// other. If there is no overlap, an UnsupportedSSHVersion
+// error is returned.
+func NegotiateVersion(local []Version, remoteAdvertised []Version) (*Version, error) {
+	var best *Version
+	for i, candidate := range remoteAdvertised {
+		// the candidate is only a valid pick if we ourselves accept it; matching
+		// protocolVersion alone is not enough, since a registered VersionConstraint can
+		// still reject it on e.g. software version.
+		if !IsVersionSupported(&candidate) {
+			continue
+		}
+		for _, ours := range local {
+			if candidate.protocolVersion != ours.protocolVersion {
+				continue
+			}
+			if best == nil || versionPrecedes(*best, candidate) {
+				best = &remoteAdvertised[i]
+			}
+		}
+	}
+	if best == nil {
+		return nil, UnsupportedSSHVersion{versionString: GetCurrentVersionString()}
+	}
+	return best, nil
+}
+
+// versionPrecedes reports whether a's software version is strictly older than b's,
+// used by NegotiateVersion to pick the highest version among several valid candidates.
+func versionPrecedes(a, b Version) bool {
+	return a.softwareVersion.Precedes(b.softwareVersion)
+}
+
 type SoftwareVersion struct {
 	ImplementationName string
 	Major              int
@@ -77,6 +514,20 @@ type SoftwareVersion struct {
 	Patch              int
 }
 
+// Precedes reports whether v is strictly older than other, comparing major, then minor,
+// then patch. It is the single source of truth for software version ordering, shared by
+// NegotiateVersion and by packages such as versioncheck that need to compare two
+// SoftwareVersions without reimplementing this comparison.
+func (v SoftwareVersion) Precedes(other SoftwareVersion) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
 type InvalidSoftwareVersion struct {
 	softwareVersionString string
 }
@@ -171,6 +622,12 @@ type Version struct {
 	protocolName    string // having the protocol name here might sound silly but there are discussions about updating the name right now and we want to support a change
 	protocolVersion ProtocolVersion
 	softwareVersion SoftwareVersion
+	// recommendedProtocolVersion and requiredProtocolVersion are announced by a peer
+	// alongside its own protocolVersion: "you may want to speak this version" and "you must
+	// at least speak this version", respectively. Both are nil when the peer announced
+	// neither, which is the case for every peer prior to this field being introduced.
+	recommendedProtocolVersion *ProtocolVersion
+	requiredProtocolVersion    *ProtocolVersion
 }
 
 func (v Version) GetProtocolVersion() ProtocolVersion {
@@ -181,6 +638,30 @@ func (v Version) GetSoftwareVersion() SoftwareVersion {
 	return v.softwareVersion
 }
 
+// GetRecommendedProtocolVersion returns the protocol version this peer recommends using,
+// or nil if it did not announce one.
+func (v Version) GetRecommendedProtocolVersion() *ProtocolVersion {
+	return v.recommendedProtocolVersion
+}
+
+// GetRequiredProtocolVersion returns the minimum protocol version this peer requires, or
+// nil if it did not announce one.
+func (v Version) GetRequiredProtocolVersion() *ProtocolVersion {
+	return v.requiredProtocolVersion
+}
+
+// SetRecommendedProtocolVersion sets the protocol version that will be announced as
+// recommended to peers, to be called before advertising this Version to them.
+func (v *Version) SetRecommendedProtocolVersion(pv ProtocolVersion) {
+	v.recommendedProtocolVersion = &pv
+}
+
+// SetRequiredProtocolVersion sets the protocol version that will be announced as required
+// to peers, to be called before advertising this Version to them.
+func (v *Version) SetRequiredProtocolVersion(pv ProtocolVersion) {
+	v.requiredProtocolVersion = &pv
+}
+
 func NewVersion(protocolName string, protocolVersion ProtocolVersion, softwareVersion SoftwareVersion) *Version {
 	return &Version{
 		protocolName:    protocolName,
@@ -199,19 +680,55 @@ func (e InvalidSSHVersion) Error() string {
 
 type UnsupportedSSHVersion struct {
 	versionString string
+	// RequiredVersion is the requiredProtocolVersion the rejected peer announced alongside
+	// its unsupported version, if any. It lets a caller surface the floor the peer asked for
+	// even though negotiation failed, instead of only being able to report our own version.
+	RequiredVersion *ProtocolVersion
 }
 
 func (e UnsupportedSSHVersion) Error() string {
+	if e.RequiredVersion != nil {
+		return fmt.Sprintf("unsupported ssh version: %s (peer requires at least %s)", e.versionString, e.RequiredVersion)
+	}
 	return fmt.Sprintf("unsupported ssh version: %s", e.versionString)
 }
 
 // GetCurrentVersionString() returns the version string to be exchanged between two
 // endpoints for version negotiation
 func GetCurrentVersionString() string {
-	return fmt.Sprintf("SSH %d.%d %s %d.%d.%d experimental_spec_version=%s", PROTOCOL_MAJOR, PROTOCOL_MINOR, SOFTWARE_IMPLEMENTATION_NAME, SOFTWARE_MAJOR, SOFTWARE_MINOR, SOFTWARE_PATCH, PROTOCOL_EXPERIMENTAL_SPEC_VERSION)
+	versionString := fmt.Sprintf("SSH %d.%d %s %d.%d.%d experimental_spec_version=%s", PROTOCOL_MAJOR, PROTOCOL_MINOR, SOFTWARE_IMPLEMENTATION_NAME, SOFTWARE_MAJOR, SOFTWARE_MINOR, SOFTWARE_PATCH, PROTOCOL_EXPERIMENTAL_SPEC_VERSION)
+	if recommended := announcedRecommendedProtocolVersion.Load(); recommended != nil {
+		versionString += fmt.Sprintf(" recommended_version=%s", recommended.String())
+	}
+	if required := announcedRequiredProtocolVersion.Load(); required != nil {
+		versionString += fmt.Sprintf(" required_version=%s", required.String())
+	}
+	return versionString
 }
 
-func ParseVersionString(versionString string) (version *Version, err error) {
+// UnknownVersionField is a warning (not a parse error) surfaced through zerolog when
+// ParseVersionString() encounters an extension field it does not recognize, after the peer
+// has already been established as running a supported spec version. It lets operators tell
+// apart "peer is too new for me" (reported as UnsupportedSSHVersion; no extension field can
+// turn that into a parse error first, since unparsable ones are skipped) from "peer sent an
+// extension field I can safely ignore".
+type UnknownVersionField struct {
+	field string
+}
+
+func (w UnknownVersionField) String() string {
+	return fmt.Sprintf("unknown version field: %s", w.field)
+}
+
+// parseVersionFields parses versionString into a Version, extracting the
+// experimental_spec_version, recommended_version and required_version extension fields
+// (like ParseVersionString does), but without applying the IsVersionSupported gate or
+// firing checkVersionAnnouncement. ParseVersionString layers the gate on top of this for a
+// single peer's handshake version; ParseSupportedVersionsHeader calls this directly for
+// each entry of a Server-Supported-Versions header, since that header by design lists
+// versions that need not all be locally supported — NegotiateVersion is what picks the
+// overlap.
+func parseVersionFields(versionString string) (*Version, error) {
 	fields := strings.Fields(versionString)
 	if len(fields) < 4 {
 		log.Error().Msgf("bad SSH version fields")
@@ -235,18 +752,74 @@ func ParseVersionString(versionString string) (version *Version, err error) {
 	// as alpha-00 and older versions do strict version checking and error as soon as the protocol version is not "3.0".
 	// This will likely disappear once we decide to remove support for alpha-00 and older versions.
 	// From that point onwards, the spec version will be announced as part of the version field.
-	if len(fields) > 4 {
-		for _, field := range fields[4:] {
-			subfields := strings.Split(field, "=")
-			if len(subfields) == 2 && subfields[0] == "experimental_spec_version" {
-				protocolVersion.ExperimentalSpecVersion = subfields[1]
-			} else {
-				log.Debug().Msgf("skipping custom version field %s", field)
+	var recommendedProtocolVersion, requiredProtocolVersion *ProtocolVersion
+	for _, field := range fields[4:] {
+		subfields := strings.SplitN(field, "=", 2)
+		if len(subfields) != 2 {
+			continue
+		}
+		switch subfields[0] {
+		case "experimental_spec_version":
+			protocolVersion.ExperimentalSpecVersion = subfields[1]
+		case "recommended_version":
+			recommended, err := ParseProtocolVersion(subfields[1])
+			if err != nil {
+				log.Debug().Msgf("skipping unparsable recommended_version field %s", subfields[1])
+				continue
+			}
+			recommendedProtocolVersion = &recommended
+		case "required_version":
+			required, err := ParseProtocolVersion(subfields[1])
+			if err != nil {
+				log.Debug().Msgf("skipping unparsable required_version field %s", subfields[1])
+				continue
 			}
+			requiredProtocolVersion = &required
 		}
+	}
+
+	version := NewVersion(protocolName, protocolVersion, softwareVersion)
+	version.recommendedProtocolVersion = recommendedProtocolVersion
+	version.requiredProtocolVersion = requiredProtocolVersion
+	return version, nil
+}
+
+func ParseVersionString(versionString string) (version *Version, err error) {
+	version, err = parseVersionFields(versionString)
+	if err != nil {
+		return nil, err
+	}
 
+	// The compatibility gate runs after parseVersionFields, on purpose: a peer that is
+	// rejected as unsupported is exactly the peer whose required_version (its hard floor)
+	// and recommended_version matter most to report, so OnRequiredVersionAvailable/
+	// OnRecommendedVersionAvailable must still fire and the floor must still be attachable
+	// to the returned error on that path.
+	if !IsVersionSupported(version) {
+		log.Error().Msgf("unsupported ssh version: %s", versionString)
+		checkVersionAnnouncement(version)
+		return nil, UnsupportedSSHVersion{versionString: versionString, RequiredVersion: version.requiredProtocolVersion}
+	}
+
+	// Starting from here, the peer is known to run a supported spec version, so any
+	// remaining extension field we don't recognize is just ignored and reported as a
+	// warning rather than as a parse error.
+	fields := strings.Fields(versionString)
+	for _, field := range fields[4:] {
+		subfields := strings.SplitN(field, "=", 2)
+		if len(subfields) != 2 {
+			log.Warn().Msgf("%s", UnknownVersionField{field: field})
+			continue
+		}
+		switch subfields[0] {
+		case "experimental_spec_version", "recommended_version", "required_version":
+			// already applied by parseVersionFields
+		default:
+			log.Warn().Msgf("%s", UnknownVersionField{field: field})
+		}
 	}
-	return NewVersion(protocolName, protocolVersion, softwareVersion), nil
+	checkVersionAnnouncement(version)
+	return version, nil
 }
 
 // GetCurrentSoftwareVersion() returns the current software version to be displayed to the user
@@ -0,0 +1,153 @@
+package ssh3
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestParseVersionConstraintMatches(t *testing.T) {
+	alpha00 := &Version{protocolVersion: ProtocolVersion{Major: 3, Minor: 0, ExperimentalSpecVersion: "alpha-00"}, softwareVersion: SoftwareVersion{Major: 0, Minor: 1, Patch: 5}}
+	alpha01 := &Version{protocolVersion: ProtocolVersion{Major: 3, Minor: 0, ExperimentalSpecVersion: "alpha-01"}, softwareVersion: SoftwareVersion{Major: 0, Minor: 1, Patch: 5}}
+	old := &Version{protocolVersion: ProtocolVersion{Major: 3, Minor: 0, ExperimentalSpecVersion: "alpha-00"}, softwareVersion: SoftwareVersion{Major: 0, Minor: 1, Patch: 3}}
+
+	tests := []struct {
+		name       string
+		constraint string
+		version    *Version
+		wantMatch  bool
+	}{
+		{"ge satisfied", ">=0.1.4", alpha00, true},
+		{"ge not satisfied", ">=0.1.4", old, false},
+		{"range both terms", ">=0.1.4, <0.2.0", alpha00, true},
+		{"range upper excluded", ">=0.1.4, <0.1.5", alpha00, false},
+		{"caret protocol version same major", "^3.0_alpha-00", alpha01, true},
+		{"caret protocol version lower major rejected", "^3.0_alpha-00", &Version{protocolVersion: ProtocolVersion{Major: 2, Minor: 0, ExperimentalSpecVersion: "alpha-00"}, softwareVersion: SoftwareVersion{Major: 0, Minor: 1, Patch: 5}}, false},
+		{"eq software version", "=0.1.5", alpha00, true},
+		{"eq software version mismatch", "=0.1.4", alpha00, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseVersionConstraint(tt.constraint)
+			if err != nil {
+				t.Fatalf("ParseVersionConstraint(%q) returned error: %s", tt.constraint, err)
+			}
+			if got := c.Matches(tt.version); got != tt.wantMatch {
+				t.Errorf("constraint %q Matches() = %v, want %v", tt.constraint, got, tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestParseVersionConstraintInvalid(t *testing.T) {
+	for _, constraint := range []string{"", ">=not-a-version", "^"} {
+		if _, err := ParseVersionConstraint(constraint); err == nil {
+			t.Errorf("ParseVersionConstraint(%q) expected an error, got nil", constraint)
+		}
+	}
+}
+
+func TestEncodeParseSupportedVersionsHeaderRoundTrip(t *testing.T) {
+	header := EncodeSupportedVersionsHeader(SupportedVersions())
+
+	versions, err := ParseSupportedVersionsHeader(header)
+	if err != nil {
+		t.Fatalf("ParseSupportedVersionsHeader returned error: %s", err)
+	}
+	if len(versions) != 1 || versions[0].protocolVersion != ThisVersion().protocolVersion {
+		t.Errorf("round-tripped header = %+v, want a single entry matching ThisVersion()", versions)
+	}
+}
+
+// TestParseSupportedVersionsHeaderSurvivesPartialOverlap reproduces a header listing one
+// entry this implementation does not support alongside one it does: the whole point of the
+// header is to let NegotiateVersion pick the overlap, so an unsupported entry must not abort
+// the parse of the rest of the list.
+func TestParseSupportedVersionsHeaderSurvivesPartialOverlap(t *testing.T) {
+	unsupported := *NewVersion("SSH", ProtocolVersion{Major: 3, Minor: 0, ExperimentalSpecVersion: "beta-01"}, SoftwareVersion{ImplementationName: SOFTWARE_IMPLEMENTATION_NAME, Major: 9, Minor: 9, Patch: 9})
+	header := EncodeSupportedVersionsHeader([]Version{unsupported, ThisVersion()})
+
+	versions, err := ParseSupportedVersionsHeader(header)
+	if err != nil {
+		t.Fatalf("ParseSupportedVersionsHeader returned error: %s, want the supported entry to survive", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected both entries to survive parsing, got %d: %+v", len(versions), versions)
+	}
+
+	best, err := NegotiateVersion(SupportedVersions(), versions)
+	if err != nil {
+		t.Fatalf("NegotiateVersion returned error: %s", err)
+	}
+	if best.protocolVersion.ExperimentalSpecVersion != ThisVersion().protocolVersion.ExperimentalSpecVersion {
+		t.Errorf("NegotiateVersion picked %+v, want the locally supported entry", best)
+	}
+}
+
+func TestNegotiateVersionNoOverlap(t *testing.T) {
+	remote := []Version{*NewVersion("SSH", ProtocolVersion{Major: 3, Minor: 0, ExperimentalSpecVersion: "beta-01"}, SoftwareVersion{Major: 9, Minor: 9, Patch: 9})}
+	if _, err := NegotiateVersion(SupportedVersions(), remote); err == nil {
+		t.Fatal("expected NegotiateVersion to fail when no candidate is locally supported")
+	}
+}
+
+// TestAnnouncedProtocolVersionsConcurrentAccess exercises SetAnnouncedRecommendedProtocolVersion/
+// SetAnnouncedRequiredProtocolVersion racing against GetCurrentVersionString() and
+// ThisVersion(), the way a long-lived server would call Set... from an admin endpoint while
+// handshakes concurrently call the getters. Run with -race.
+func TestAnnouncedProtocolVersionsConcurrentAccess(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			SetAnnouncedRecommendedProtocolVersion(ProtocolVersion{Major: 3, Minor: 1, ExperimentalSpecVersion: "alpha-00"})
+		}()
+		go func() {
+			defer wg.Done()
+			SetAnnouncedRequiredProtocolVersion(ProtocolVersion{Major: 3, Minor: 0, ExperimentalSpecVersion: "alpha-00"})
+		}()
+		go func() {
+			defer wg.Done()
+			_ = GetCurrentVersionString()
+			_ = ThisVersion()
+		}()
+	}
+	wg.Wait()
+}
+
+// TestParseVersionStringRejectsUnsupportedSpecVersionBeforeUnknownFields reproduces a peer
+// announcing a spec version this implementation does not support, together with an
+// extension field (required_version) whose grammar an older binary wouldn't understand: the
+// compatibility gate must reject the peer before that field's grammar is interpreted as an
+// invalid one, and the peer's required_version must still be surfaced on the error.
+func TestParseVersionStringRejectsUnsupportedSpecVersionBeforeUnknownFields(t *testing.T) {
+	versionString := "SSH 3.0 " + SOFTWARE_IMPLEMENTATION_NAME + " 0.1.5 experimental_spec_version=beta-99 required_version=3.0_alpha-00"
+
+	version, err := ParseVersionString(versionString)
+	if version != nil {
+		t.Fatalf("expected no version to be returned, got %+v", version)
+	}
+	unsupported, ok := err.(UnsupportedSSHVersion)
+	if !ok {
+		t.Fatalf("expected UnsupportedSSHVersion, got %T: %s", err, err)
+	}
+	if unsupported.RequiredVersion == nil || unsupported.RequiredVersion.String() != "3.0_alpha-00" {
+		t.Errorf("expected the rejection to carry the peer's required_version, got %+v", unsupported.RequiredVersion)
+	}
+}
+
+func TestNegotiateVersionPicksHighestOverlap(t *testing.T) {
+	this := ThisVersion()
+	older, newer := this, this
+	older.softwareVersion.Patch--
+	newer.softwareVersion.Patch++
+
+	best, err := NegotiateVersion([]Version{this}, []Version{older, newer})
+	if err != nil {
+		t.Fatalf("NegotiateVersion returned error: %s", err)
+	}
+	if best.softwareVersion.Patch != newer.softwareVersion.Patch {
+		t.Errorf("NegotiateVersion picked patch %d, want the highest overlapping patch %d", best.softwareVersion.Patch, newer.softwareVersion.Patch)
+	}
+}
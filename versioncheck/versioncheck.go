@@ -0,0 +1,184 @@
+// Package versioncheck implements a small, well-known HTTPS endpoint through which an SSH3
+// server publishes the versions it currently accepts, plus a client-side helper to probe
+// that endpoint before dialing. This is the SSH3 analogue of Storj's versioncontrol peer: a
+// single place that centrally publishes the minimum accepted version per deployment,
+// queried proactively instead of discovered lazily when a handshake fails with
+// ssh3.UnsupportedSSHVersion.
+package versioncheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/francoismichel/ssh3"
+)
+
+// WellKnownPath is the path at which a compliant SSH3 server serves its ServerVersionInfo document.
+const WellKnownPath = "/.well-known/ssh3-version"
+
+// ServerVersionInfo is the JSON document served at WellKnownPath.
+type ServerVersionInfo struct {
+	// ServerVersion is the server's own ssh3.GetCurrentVersionString().
+	ServerVersion string `json:"server_version"`
+	// AcceptedProtocolVersions lists the protocol version strings the server currently accepts from clients.
+	AcceptedProtocolVersions []string `json:"accepted_protocol_versions"`
+	// MinimumRequiredVersion, when non-empty, is the lowest client software version the
+	// server will accept, below which clients should upgrade before even attempting to dial.
+	MinimumRequiredVersion string `json:"minimum_required_version,omitempty"`
+	// UpgradeURL points users at release notes or a download page when MinimumRequiredVersion
+	// is not met.
+	UpgradeURL string `json:"upgrade_url,omitempty"`
+	// Changelog is an optional short, human-readable note about what changed since
+	// MinimumRequiredVersion, surfaced to users alongside UpgradeURL.
+	Changelog string `json:"changelog,omitempty"`
+}
+
+// NewServerVersionInfo builds the ServerVersionInfo document for the versions currently
+// accepted by this server.
+func NewServerVersionInfo(accepted []ssh3.Version, minimumRequired *ssh3.SoftwareVersion, upgradeURL, changelog string) ServerVersionInfo {
+	info := ServerVersionInfo{
+		ServerVersion: ssh3.GetCurrentVersionString(),
+		UpgradeURL:    upgradeURL,
+		Changelog:     changelog,
+	}
+	for _, v := range accepted {
+		info.AcceptedProtocolVersions = append(info.AcceptedProtocolVersions, v.GetProtocolVersion().String())
+	}
+	if minimumRequired != nil {
+		info.MinimumRequiredVersion = minimumRequired.String()
+	}
+	return info
+}
+
+// UnexpectedStatus is returned by Probe when the version-check endpoint responds with
+// anything other than 200 OK.
+type UnexpectedStatus struct {
+	status string
+}
+
+func (e UnexpectedStatus) Error() string {
+	return fmt.Sprintf("version-check endpoint returned status %s", e.status)
+}
+
+// Handler returns an http.HandlerFunc serving info as the WellKnownPath JSON document. It
+// also sets the ssh3.SSHServerSupportedVersionsHeader to accepted, encoded with
+// ssh3.EncodeSupportedVersionsHeader, so that a client whose handshake is rejected with
+// ssh3.UnsupportedSSHVersion can probe this same endpoint, read the header via Negotiate,
+// and retry with a version both sides accept instead of giving up.
+func Handler(info ServerVersionInfo, accepted []ssh3.Version) http.HandlerFunc {
+	supportedVersionsHeader := ssh3.EncodeSupportedVersionsHeader(accepted)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(ssh3.SSHServerSupportedVersionsHeader, supportedVersionsHeader)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(info); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// get issues a GET request to url and returns the response once its status has been
+// confirmed to be 200 OK, so that Probe and Negotiate don't each duplicate the
+// request/status-check boilerplate. The caller is responsible for closing resp.Body.
+func get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, UnexpectedStatus{status: resp.Status}
+	}
+	return resp, nil
+}
+
+// Probe fetches and decodes the ServerVersionInfo document served at url, which should
+// point at a server's WellKnownPath. A client can call this before opening the QUIC session
+// to short-circuit an incompatible dial and print an actionable upgrade message built from
+// UpgradeURL, Changelog and MinimumRequiredVersion.
+func Probe(ctx context.Context, url string) (*ServerVersionInfo, error) {
+	resp, err := get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var info ServerVersionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Negotiate probes url and, using the ssh3.SSHServerSupportedVersionsHeader the server set
+// on the response alongside its ServerVersionInfo document, returns the version a client
+// dialing that server should use: the best overlap between local (typically
+// ssh3.SupportedVersions()) and the versions the server advertised. It is meant to be called
+// from the client dial path before opening the QUIC session, instead of dialing straight
+// away and only discovering the mismatch from an ssh3.UnsupportedSSHVersion handshake error.
+func Negotiate(ctx context.Context, url string, local []ssh3.Version) (*ssh3.Version, error) {
+	resp, err := get(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	remoteAdvertised, err := ssh3.ParseSupportedVersionsHeader(resp.Header.Get(ssh3.SSHServerSupportedVersionsHeader))
+	if err != nil {
+		return nil, err
+	}
+	return ssh3.NegotiateVersion(local, remoteAdvertised)
+}
+
+// VersionRolloutPolicy lets a server operator declare a rollout schedule: clients running a
+// software version older than MinimumVersion are rejected once the current time is at or
+// after EffectiveDate. Before EffectiveDate, older clients are still accepted, so the policy
+// can be announced ahead of enforcement (e.g. via ServerVersionInfo.UpgradeURL).
+type VersionRolloutPolicy struct {
+	MinimumVersion ssh3.SoftwareVersion
+	EffectiveDate  time.Time
+}
+
+// Rejects reports whether, at checkTime, clientVersion should be rejected under this
+// rollout policy. It is meant to be called from the HTTP upgrade handler, alongside
+// ssh3.IsVersionSupported, before a session is established.
+func (p VersionRolloutPolicy) Rejects(clientVersion ssh3.SoftwareVersion, checkTime time.Time) bool {
+	if checkTime.Before(p.EffectiveDate) {
+		return false
+	}
+	return clientVersion.Precedes(p.MinimumVersion)
+}
+
+// RolloutRejected is returned by EnforceHandshake when a client version is turned away by a
+// VersionRolloutPolicy, as opposed to ssh3.UnsupportedSSHVersion which covers protocol-level
+// incompatibility.
+type RolloutRejected struct {
+	ClientVersion ssh3.SoftwareVersion
+	Policy        VersionRolloutPolicy
+}
+
+func (e RolloutRejected) Error() string {
+	return fmt.Sprintf("client software version %s rejected by rollout policy in effect since %s (minimum %s)",
+		e.ClientVersion, e.Policy.EffectiveDate.Format(time.RFC3339), e.Policy.MinimumVersion)
+}
+
+// EnforceHandshake is the single check an SSH3 server's HTTP upgrade handler should run once
+// it has parsed the client's peer Version with ssh3.ParseVersionString: it layers policy, an
+// optional VersionRolloutPolicy, on top of the ssh3.IsVersionSupported check
+// ssh3.ParseVersionString already applied, rejecting clients that are protocol-compatible
+// but whose software version predates the policy's rollout floor. policy may be nil to skip
+// rollout enforcement entirely; checkTime is the wall-clock time to evaluate it against.
+func EnforceHandshake(peerVersion *ssh3.Version, policy *VersionRolloutPolicy, checkTime time.Time) error {
+	if policy == nil {
+		return nil
+	}
+	clientVersion := peerVersion.GetSoftwareVersion()
+	if policy.Rejects(clientVersion, checkTime) {
+		return RolloutRejected{ClientVersion: clientVersion, Policy: *policy}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package versioncheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/francoismichel/ssh3"
+)
+
+func TestProbeAndNegotiate(t *testing.T) {
+	info := NewServerVersionInfo(ssh3.SupportedVersions(), nil, "https://example.com/upgrade", "")
+	server := httptest.NewServer(Handler(info, ssh3.SupportedVersions()))
+	defer server.Close()
+
+	got, err := Probe(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Probe returned error: %s", err)
+	}
+	if got.ServerVersion != ssh3.GetCurrentVersionString() {
+		t.Errorf("Probe ServerVersion = %q, want %q", got.ServerVersion, ssh3.GetCurrentVersionString())
+	}
+
+	best, err := Negotiate(context.Background(), server.URL, ssh3.SupportedVersions())
+	if err != nil {
+		t.Fatalf("Negotiate returned error: %s", err)
+	}
+	if best.GetProtocolVersion() != ssh3.ThisVersion().GetProtocolVersion() {
+		t.Errorf("Negotiate picked %+v, want ThisVersion()'s protocol version", best.GetProtocolVersion())
+	}
+}
+
+func TestProbeUnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	if _, err := Probe(context.Background(), server.URL); err == nil {
+		t.Fatal("expected Probe to fail on a non-200 status")
+	} else if _, ok := err.(UnexpectedStatus); !ok {
+		t.Errorf("expected UnexpectedStatus, got %T: %s", err, err)
+	}
+}
+
+func TestVersionRolloutPolicyRejectsAndEnforceHandshake(t *testing.T) {
+	policy := VersionRolloutPolicy{
+		MinimumVersion: ssh3.SoftwareVersion{Major: 0, Minor: 1, Patch: 5},
+		EffectiveDate:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	oldClient := ssh3.SoftwareVersion{Major: 0, Minor: 1, Patch: 4}
+
+	if policy.Rejects(oldClient, policy.EffectiveDate.Add(-time.Hour)) {
+		t.Error("expected the policy not to reject before its EffectiveDate")
+	}
+	if !policy.Rejects(oldClient, policy.EffectiveDate.Add(time.Hour)) {
+		t.Error("expected the policy to reject an older client once EffectiveDate has passed")
+	}
+
+	peer := ssh3.NewVersion("SSH", ssh3.ThisVersion().GetProtocolVersion(), oldClient)
+	if err := EnforceHandshake(peer, &policy, policy.EffectiveDate.Add(time.Hour)); err == nil {
+		t.Fatal("expected EnforceHandshake to reject an older client once EffectiveDate has passed")
+	} else if _, ok := err.(RolloutRejected); !ok {
+		t.Errorf("expected RolloutRejected, got %T: %s", err, err)
+	}
+
+	if err := EnforceHandshake(peer, nil, policy.EffectiveDate.Add(time.Hour)); err != nil {
+		t.Errorf("expected EnforceHandshake to pass through with a nil policy, got %s", err)
+	}
+}